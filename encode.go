@@ -1,10 +1,34 @@
 package imgo
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"image"
+	"image/draw"
+	"io"
 )
 
+// BI_RGB and BI_BITFIELDS are the BMP compression values used by the
+// classic and the v4/v5 DIB headers respectively
+const (
+	biRGB       uint32 = 0
+	biBitfields uint32 = 3
+)
+
+// R/G/B/A bit masks written into the BITMAPV4HEADER/BITMAPV5HEADER so
+// 32bpp pixels round-trip through BMP with their alpha channel intact
+const (
+	maskR uint32 = 0x00FF0000
+	maskG uint32 = 0x0000FF00
+	maskB uint32 = 0x000000FF
+	maskA uint32 = 0xFF000000
+)
+
+// lcsSRGB is LCS_sRGB, the CSType value that marks a BITMAPV4HEADER's
+// color space as sRGB so the endpoints/gamma fields are ignored
+const lcsSRGB uint32 = 0x73524742
+
 // EncodeImg encode the image.Image return pix and stride
 func EncodeImg(m image.Image) (pix []uint8, stride int, err error) {
 	d := m.Bounds().Size()
@@ -108,14 +132,240 @@ func EncodeImg(m image.Image) (pix []uint8, stride int, err error) {
 	return
 }
 
-// ConvertToRGBA convert the image.Image to *image.RGBA
-func ConvertToRGBA(img image.Image) (r *image.RGBA) {
-	pix, stride, _ := EncodeImg(img)
-	return &image.RGBA{
-		Pix:    pix,
-		Stride: stride,
-		Rect:   image.Rect(0, 0, Width(img), Height(img)),
+// ConvertToRGBA convert any image.Image to *image.RGBA, using
+// draw.Draw so it works for every concrete image type and preserves
+// alpha correctly; the previous implementation reused EncodeImg's
+// BMP-oriented pix slice, which is palettized for Gray/Paletted images
+// and nil for anything else
+func ConvertToRGBA(img image.Image) *image.RGBA {
+	if r, ok := img.(*image.RGBA); ok {
+		return r
 	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// ConvertToNRGBA convert any image.Image to *image.NRGBA, using
+// draw.Draw so it works for every concrete image type and preserves
+// alpha correctly
+func ConvertToNRGBA(img image.Image) *image.NRGBA {
+	return toNRGBA(img)
+}
+
+// InvertColors return img with its R/G/B channels inverted, alpha is
+// left untouched. It normalizes img to NRGBA (straight, not
+// premultiplied, alpha) first and inverts those straight values, so
+// the result re-encodes correctly through formats without an alpha
+// channel (e.g. Encode's jpeg path) instead of producing the
+// over-saturated colors that inverting premultiplied RGBA bytes
+// directly would cause
+func InvertColors(img image.Image) image.Image {
+	src := toNRGBA(img)
+	b := src.Bounds()
+	dst := image.NewNRGBA(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			so := src.PixOffset(x, y)
+			do := dst.PixOffset(x, y)
+			dst.Pix[do+0] = 255 - src.Pix[so+0]
+			dst.Pix[do+1] = 255 - src.Pix[so+1]
+			dst.Pix[do+2] = 255 - src.Pix[so+2]
+			dst.Pix[do+3] = src.Pix[so+3]
+		}
+	}
+
+	return dst
+}
+
+// EncodeImgV5 encode the image.Image to w as a BMP file using an
+// extended DIB header (a 108 byte BITMAPV4HEADER, or the 124 byte
+// BITMAPV5HEADER when v5 is true) that carries explicit R/G/B/A bit
+// masks with BI_BITFIELDS compression, so 32bpp images keep their
+// alpha channel, which EncodeImg's classic 40 byte header has no mask for;
+// topDown stores a negative height and writes rows top to bottom
+// instead of the classic bottom-up row order
+func EncodeImgV5(w io.Writer, m image.Image, v5, topDown bool) error {
+	d := m.Bounds().Size()
+	if d.X <= 0 || d.Y <= 0 {
+		return errors.New("imgo: invalid bounds")
+	}
+
+	dibSize := uint32(108)
+	if v5 {
+		dibSize = 124
+	}
+
+	nrgba := toNRGBA(m)
+	step := 4 * d.X
+	imageSize := uint32(d.Y * step)
+	pixOffset := 14 + dibSize
+	fileSize := pixOffset + imageSize
+
+	fh := make([]byte, 14)
+	fh[0], fh[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(fh[2:6], fileSize)
+	binary.LittleEndian.PutUint32(fh[10:14], pixOffset)
+	if _, err := w.Write(fh); err != nil {
+		return err
+	}
+
+	height := int32(d.Y)
+	if topDown {
+		height = -height
+	}
+
+	dh := make([]byte, dibSize)
+	binary.LittleEndian.PutUint32(dh[0:4], dibSize)
+	binary.LittleEndian.PutUint32(dh[4:8], uint32(d.X))
+	binary.LittleEndian.PutUint32(dh[8:12], uint32(height))
+	binary.LittleEndian.PutUint16(dh[12:14], 1)
+	binary.LittleEndian.PutUint16(dh[14:16], 32)
+	binary.LittleEndian.PutUint32(dh[16:20], biBitfields)
+	binary.LittleEndian.PutUint32(dh[20:24], imageSize)
+	binary.LittleEndian.PutUint32(dh[40:44], maskR)
+	binary.LittleEndian.PutUint32(dh[44:48], maskG)
+	binary.LittleEndian.PutUint32(dh[48:52], maskB)
+	binary.LittleEndian.PutUint32(dh[52:56], maskA)
+	binary.LittleEndian.PutUint32(dh[56:60], lcsSRGB)
+	// endpoints (36 bytes) and gamma (12 bytes) stay zero, they're
+	// ignored by readers when CSType is LCS_sRGB
+	if _, err := w.Write(dh); err != nil {
+		return err
+	}
+
+	row := make([]byte, step)
+	writeRow := func(y int) error {
+		src := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+step]
+		for x := 0; x < d.X; x++ {
+			row[x*4+0] = src[x*4+2] // B
+			row[x*4+1] = src[x*4+1] // G
+			row[x*4+2] = src[x*4+0] // R
+			row[x*4+3] = src[x*4+3] // A
+		}
+		_, err := w.Write(row)
+		return err
+	}
+
+	if topDown {
+		for y := 0; y < d.Y; y++ {
+			if err := writeRow(y); err != nil {
+				return err
+			}
+		}
+	} else {
+		for y := d.Y - 1; y >= 0; y-- {
+			if err := writeRow(y); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DecodeImgV5 decode a BMP file written by EncodeImgV5, understanding
+// both the 108 byte BITMAPV4HEADER and the 124 byte BITMAPV5HEADER,
+// and reconstructs the alpha channel from the stored bit masks
+func DecodeImgV5(r io.Reader) (image.Image, error) {
+	fh := make([]byte, 14)
+	if _, err := io.ReadFull(r, fh); err != nil {
+		return nil, err
+	}
+	if fh[0] != 'B' || fh[1] != 'M' {
+		return nil, errors.New("imgo: not a BMP file")
+	}
+	pixOffset := binary.LittleEndian.Uint32(fh[10:14])
+
+	dibSizeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(r, dibSizeBuf); err != nil {
+		return nil, err
+	}
+	dibSize := binary.LittleEndian.Uint32(dibSizeBuf)
+	if dibSize != 108 && dibSize != 124 {
+		return nil, fmt.Errorf("imgo: unsupported DIB header size %d", dibSize)
+	}
+
+	rest := make([]byte, dibSize-4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	dh := append(dibSizeBuf, rest...)
+
+	width := int(binary.LittleEndian.Uint32(dh[4:8]))
+	rawHeight := int32(binary.LittleEndian.Uint32(dh[8:12]))
+	topDown := rawHeight < 0
+	height := int(rawHeight)
+	if topDown {
+		height = -height
+	}
+	bpp := binary.LittleEndian.Uint16(dh[14:16])
+	compression := binary.LittleEndian.Uint32(dh[16:20])
+	if bpp != 32 || compression != biBitfields {
+		return nil, errors.New("imgo: DecodeImgV5 only supports 32bpp BI_BITFIELDS images")
+	}
+	redMask := binary.LittleEndian.Uint32(dh[40:44])
+	greenMask := binary.LittleEndian.Uint32(dh[44:48])
+	blueMask := binary.LittleEndian.Uint32(dh[48:52])
+	alphaMask := binary.LittleEndian.Uint32(dh[52:56])
+
+	headerLen := int64(14 + dibSize)
+	if skip := int64(pixOffset) - headerLen; skip > 0 {
+		if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+			return nil, err
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	step := 4 * width
+	row := make([]byte, step)
+	for i := 0; i < height; i++ {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, err
+		}
+
+		y := height - 1 - i
+		if topDown {
+			y = i
+		}
+
+		for x := 0; x < width; x++ {
+			px := binary.LittleEndian.Uint32(row[x*4 : x*4+4])
+			o := img.PixOffset(x, y)
+			img.Pix[o+0] = uint8((px & redMask) >> maskShift(redMask))
+			img.Pix[o+1] = uint8((px & greenMask) >> maskShift(greenMask))
+			img.Pix[o+2] = uint8((px & blueMask) >> maskShift(blueMask))
+			img.Pix[o+3] = uint8((px & alphaMask) >> maskShift(alphaMask))
+		}
+	}
+
+	return img, nil
+}
+
+// maskShift return the number of trailing zero bits in mask, used to
+// shift a packed BI_BITFIELDS channel down into a uint8
+func maskShift(mask uint32) uint {
+	shift := uint(0)
+	for mask != 0 && mask&1 == 0 {
+		mask >>= 1
+		shift++
+	}
+	return shift
+}
+
+// toNRGBA convert any image.Image to *image.NRGBA via draw.Draw
+func toNRGBA(m image.Image) *image.NRGBA {
+	if n, ok := m.(*image.NRGBA); ok {
+		return n
+	}
+
+	b := m.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), m, b.Min, draw.Src)
+	return dst
 }
 
 type header struct {
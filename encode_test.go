@@ -0,0 +1,107 @@
+package imgo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestEncodeImgV5(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	src.Set(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 128})
+	src.Set(3, 3, color.NRGBA{R: 200, G: 100, B: 50, A: 64})
+
+	var buf bytes.Buffer
+	err := EncodeImgV5(&buf, src, true, false)
+	tt.Nil(t, err)
+
+	out, err := DecodeImgV5(&buf)
+	tt.Nil(t, err)
+
+	got, ok := out.(*image.NRGBA)
+	tt.Equal(t, true, ok)
+	tt.Equal(t, src.NRGBAAt(0, 0), got.NRGBAAt(0, 0))
+	tt.Equal(t, src.NRGBAAt(3, 3), got.NRGBAAt(3, 3))
+}
+
+func TestEncodeImgV5TopDown(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	src.Set(0, 0, color.NRGBA{R: 5, G: 6, B: 7, A: 255})
+	src.Set(0, 2, color.NRGBA{R: 250, G: 251, B: 252, A: 10})
+
+	var buf bytes.Buffer
+	err := EncodeImgV5(&buf, src, false, true)
+	tt.Nil(t, err)
+
+	out, err := DecodeImgV5(&buf)
+	tt.Nil(t, err)
+
+	got := out.(*image.NRGBA)
+	tt.Equal(t, src.NRGBAAt(0, 0), got.NRGBAAt(0, 0))
+	tt.Equal(t, src.NRGBAAt(0, 2), got.NRGBAAt(0, 2))
+}
+
+// TestConvertToRGBA checks that a semi-transparent NRGBA pixel converts
+// to the same premultiplied color.RGBA value that color.RGBAModel gives
+func TestConvertToRGBA(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(1, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 128})
+
+	dst := ConvertToRGBA(src)
+	want := color.RGBAModel.Convert(src.At(1, 0)).(color.RGBA)
+	tt.Equal(t, want, dst.RGBAAt(1, 0))
+}
+
+// TestConvertToNRGBA checks that an opaque pixel of an *image.RGBA
+// round-trips through ConvertToNRGBA unchanged
+func TestConvertToNRGBA(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 1, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+
+	dst := ConvertToNRGBA(src)
+	tt.Equal(t, color.NRGBA{R: 10, G: 20, B: 30, A: 255}, dst.NRGBAAt(0, 1))
+}
+
+// TestInvertColors checks that InvertColors un-premultiplies a
+// premultiplied *image.RGBA source before inverting its R/G/B channels,
+// leaving alpha untouched
+func TestInvertColors(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	src.Set(0, 0, color.RGBA{R: 100, G: 0, B: 0, A: 128})
+
+	// the straight (un-premultiplied) color src's premultiplied pixel
+	// represents; InvertColors must invert these values, not the raw
+	// premultiplied bytes
+	straight := color.NRGBAModel.Convert(src.At(0, 0)).(color.NRGBA)
+
+	out := InvertColors(src)
+	got, ok := out.(*image.NRGBA)
+	tt.Equal(t, true, ok)
+
+	want := color.NRGBA{R: 255 - straight.R, G: 255 - straight.G, B: 255 - straight.B, A: straight.A}
+	tt.Equal(t, want, got.NRGBAAt(0, 0))
+}
+
+// TestBMPAlphaOption checks that EncodeOptions.BMPAlpha actually
+// routes the registry's "bmp" encoder to EncodeImgV5, and that
+// decodeBMP auto-detects the resulting v4/v5 header back
+func TestBMPAlphaOption(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 77})
+
+	opts := DefaultEncodeOptions()
+	opts.BMPAlpha = true
+
+	var buf bytes.Buffer
+	err := EncodeWith(&buf, src, "bmp", opts)
+	tt.Nil(t, err)
+
+	img, err := decodeBMP(bytes.NewReader(buf.Bytes()))
+	tt.Nil(t, err)
+
+	got := img.(*image.NRGBA)
+	tt.Equal(t, uint8(77), got.NRGBAAt(0, 0).A)
+}
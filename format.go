@@ -0,0 +1,195 @@
+package imgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	xwebp "golang.org/x/image/webp"
+)
+
+// Decoder decode an image from r
+type Decoder func(r io.Reader) (image.Image, error)
+
+// Encoder encode img to w; opts is whatever EncodeWith was called
+// with (an EncodeOptions for the builtin formats), encoders that
+// don't need it are free to ignore it
+type Encoder func(w io.Writer, img image.Image, opts interface{}) error
+
+type format struct {
+	name   string
+	magic  []string
+	decode Decoder
+	encode Encoder
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []format
+)
+
+// RegisterFormat register a decoder/encoder pair under name, so that
+// Decode, Encode, Read, Save, ToBytes, ByteToImg and StrToImg all pick
+// it up via the registry. magic holds the format's magic-byte
+// prefixes, following image.RegisterFormat's convention (a '?' byte
+// matches any byte) — they're also forwarded to image.RegisterFormat
+// so third-party code calling the stdlib's image.Decode directly
+// picks up the format too. Third-party packages can call this once to
+// plug in formats such as AVIF, HEIF or PSD without patching imgo.
+// Safe to call concurrently with Decode/EncodeWith/ByteToImg/StrToImg.
+func RegisterFormat(name string, magic []string, decode Decoder, encode Encoder) {
+	formatsMu.Lock()
+	formats = append(formats, format{name: name, magic: magic, decode: decode, encode: encode})
+	formatsMu.Unlock()
+
+	for _, m := range magic {
+		image.RegisterFormat(name, m, decode, nil)
+	}
+}
+
+func lookupFormat(name string) (format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, f := range formats {
+		if f.name == name {
+			return f, true
+		}
+	}
+
+	return format{}, false
+}
+
+// sniffFormat peeks at r looking for a registered format's magic
+// bytes, the same way image.Decode sniffs the stdlib's registry, but
+// against imgo's own formats so Decode/ByteToImg/StrToImg don't rely
+// on RegisterFormat's image.RegisterFormat side effect to find a
+// decoder (e.g. BMP v4/v5 via decodeBMP)
+func sniffFormat(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	formatsMu.RLock()
+	snapshot := make([]format, len(formats))
+	copy(snapshot, formats)
+	formatsMu.RUnlock()
+
+	for _, f := range snapshot {
+		for _, m := range f.magic {
+			head, err := br.Peek(len(m))
+			if err == nil && matchMagic(head, m) {
+				return f.decode(br)
+			}
+		}
+	}
+
+	return nil, errors.New("imgo: sniffFormat: unrecognized format")
+}
+
+// matchMagic reports whether data starts with pattern, treating a '?'
+// byte in pattern as a wildcard, following image.RegisterFormat's
+// magic-byte convention
+func matchMagic(data []byte, pattern string) bool {
+	if len(data) < len(pattern) {
+		return false
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '?' && data[i] != pattern[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func init() {
+	RegisterFormat("jpeg", []string{"\xff\xd8"},
+		func(r io.Reader) (image.Image, error) { return jpeg.Decode(r) },
+		func(w io.Writer, img image.Image, opts interface{}) error {
+			o := optsOrDefault(opts)
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: o.Quality})
+		})
+
+	RegisterFormat("png", []string{"\x89PNG\r\n\x1a\n"},
+		func(r io.Reader) (image.Image, error) { return png.Decode(r) },
+		func(w io.Writer, img image.Image, opts interface{}) error {
+			o := optsOrDefault(opts)
+			enc := png.Encoder{CompressionLevel: o.CompressionLevel}
+			return enc.Encode(w, img)
+		})
+
+	RegisterFormat("gif", []string{"GIF8?a"},
+		func(r io.Reader) (image.Image, error) { return gif.Decode(r) },
+		func(w io.Writer, img image.Image, opts interface{}) error {
+			o := optsOrDefault(opts)
+			return gif.Encode(w, img, &gif.Options{
+				NumColors: o.NumColors,
+				Quantizer: o.Quantizer,
+				Drawer:    o.Drawer,
+			})
+		})
+
+	RegisterFormat("bmp", []string{"BM"}, decodeBMP, encodeBMP)
+
+	RegisterFormat("tiff", []string{"MM\x00*", "II*\x00"},
+		func(r io.Reader) (image.Image, error) { return tiff.Decode(r) },
+		func(w io.Writer, img image.Image, opts interface{}) error {
+			o := optsOrDefault(opts)
+			return tiff.Encode(w, img, &tiff.Options{Compression: o.Compression})
+		})
+
+	// Decoding is pure Go (golang.org/x/image/webp); encoding is
+	// provided by encodeWebP, which is cgo-only (see webp_cgo.go) with
+	// a non-cgo stub (webp_nocgo.go) so importing imgo doesn't force
+	// cgo on callers who never touch webp encoding
+	RegisterFormat("webp", []string{"RIFF????WEBP"},
+		func(r io.Reader) (image.Image, error) { return xwebp.Decode(r) },
+		encodeWebP)
+}
+
+// decodeBMP peeks at the DIB header size to tell a classic
+// BITMAPINFOHEADER bitmap from one written by EncodeImgV5, and routes
+// to bmp.Decode or DecodeImgV5 accordingly
+func decodeBMP(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	head, err := br.Peek(18)
+	if err == nil && len(head) == 18 {
+		dibSize := binary.LittleEndian.Uint32(head[14:18])
+		if dibSize == 108 || dibSize == 124 {
+			return DecodeImgV5(br)
+		}
+	}
+
+	return bmp.Decode(br)
+}
+
+// encodeBMP uses EncodeImgV5 when opts.BMPAlpha is set, so the 32bpp
+// alpha channel survives the round trip, otherwise it falls back to
+// the classic golang.org/x/image/bmp encoder
+func encodeBMP(w io.Writer, img image.Image, opts interface{}) error {
+	o := optsOrDefault(opts)
+	if o.BMPAlpha {
+		return EncodeImgV5(w, img, true, o.TopDown)
+	}
+
+	return bmp.Encode(w, img)
+}
+
+// optsOrDefault type-asserts opts to EncodeOptions, falling back to
+// DefaultEncodeOptions for callers that pass nil or another type
+func optsOrDefault(opts interface{}) EncodeOptions {
+	if o, ok := opts.(EncodeOptions); ok {
+		return o
+	}
+
+	return DefaultEncodeOptions()
+}
@@ -0,0 +1,55 @@
+package imgo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("imgotest", []string{"IMGOTEST"},
+		func(r io.Reader) (image.Image, error) {
+			return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+		},
+		func(w io.Writer, img image.Image, opts interface{}) error {
+			_, err := w.Write([]byte("IMGOTEST"))
+			return err
+		})
+
+	ft, ok := lookupFormat("imgotest")
+	tt.Equal(t, true, ok)
+
+	var buf bytes.Buffer
+	err := ft.encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)), nil)
+	tt.Nil(t, err)
+	tt.Equal(t, "IMGOTEST", buf.String())
+}
+
+// TestDecodeSniff checks that Decode falls back to sniffing the magic
+// bytes when fm is empty or not a registered format
+func TestDecodeSniff(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	src.Set(1, 1, color.RGBA{R: 200, G: 10, B: 10, A: 255})
+
+	tmp, err := ioutil.TempFile("", "imgo-sniff-*.bin")
+	tt.Nil(t, err)
+	defer os.Remove(tmp.Name())
+
+	err = Encode(tmp, src, "png")
+	tt.Nil(t, err)
+	tt.Nil(t, tmp.Close())
+
+	f, err := os.Open(tmp.Name())
+	tt.Nil(t, err)
+	defer f.Close()
+
+	img, err := Decode(f, "bin")
+	tt.Nil(t, err)
+	tt.Equal(t, 4, img.Bounds().Dx())
+}
@@ -20,7 +20,6 @@ package imgo
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"image"
 	"io"
@@ -29,13 +28,8 @@ import (
 
 	"encoding/base64"
 	"image/color"
-	"image/gif"
-	"image/jpeg"
 	"image/png"
 	"io/ioutil"
-
-	"golang.org/x/image/bmp"
-	"golang.org/x/image/tiff"
 )
 
 var (
@@ -123,11 +117,18 @@ func SaveToJpeg(path string, img image.Image) error {
 	}
 	defer f.Close()
 
-	opt := jpeg.Options{
-		Quality: 90,
+	return EncodeWith(f, img, "jpeg", DefaultEncodeOptions())
+}
+
+// SaveToWebP create a webp file with the image.Image
+func SaveToWebP(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
-	err = jpeg.Encode(f, img, &opt)
-	return err
+	defer f.Close()
+
+	return EncodeWith(f, img, "webp", DefaultEncodeOptions())
 }
 
 // Create create a file by path
@@ -155,7 +156,7 @@ func Read(path string) (image.Image, error) {
 
 func getFm(path string) string {
 	p := strings.Split(path, ".")
-	return p[len(p)-1]
+	return strings.ToLower(p[len(p)-1])
 }
 
 // ReadPNG read png return image.Image
@@ -189,41 +190,21 @@ func Destroy(filePath string) error {
 	return os.Remove(filePath)
 }
 
-// Decode decode image from file
+// Decode decode image from file, fm selects the format via the
+// registry (see RegisterFormat); when fm is empty or not registered
+// it falls back to sniffing the file's magic bytes against the same
+// registry
 func Decode(f *os.File, fm string) (image.Image, error) {
-	switch fm {
-	case "jpeg":
-		return jpeg.Decode(f)
-	case "png":
-		return png.Decode(f)
-	case "gif":
-		return gif.Decode(f)
-	case "bmp":
-		return bmp.Decode(f)
-	case "tiff":
-		return tiff.Decode(f)
-	default:
-		return nil, errors.New("Decode: Error format")
+	if ft, ok := lookupFormat(fm); ok {
+		return ft.decode(f)
 	}
-	// return nil, nil
+
+	return sniffFormat(f)
 }
 
 // Encode encode image to buf
 func Encode(out io.Writer, subImg image.Image, fm string) error {
-	switch fm {
-	case "jpeg":
-		return jpeg.Encode(out, subImg, nil)
-	case "png":
-		return png.Encode(out, subImg)
-	case "gif":
-		return gif.Encode(out, subImg, &gif.Options{})
-	case "bmp":
-		return bmp.Encode(out, subImg)
-	case "tiff":
-		return tiff.Encode(out, subImg, &tiff.Options{})
-	default:
-		return errors.New("Encode: ERROR FORMAT")
-	}
+	return EncodeWith(out, subImg, fm, DefaultEncodeOptions())
 }
 
 // ToString tostring image.Image
@@ -321,15 +302,12 @@ func ToStringImg(img image.Image) string {
 // StrToImg convert base64 string to image.Image
 func StrToImg(data string) (image.Image, error) {
 	reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))
-	m, _, err := image.Decode(reader)
-
-	return m, err
+	return sniffFormat(reader)
 }
 
 // ByteToImg convert []byte to image.Image
 func ByteToImg(b []byte) (image.Image, error) {
-	img, _, err := image.Decode(bytes.NewReader(b))
-	return img, err
+	return sniffFormat(bytes.NewReader(b))
 }
 
 // OpenBase64 return a base64 string from image file
@@ -1,6 +1,8 @@
 package imgo
 
 import (
+	"image"
+	"image/color"
 	"testing"
 
 	"github.com/vcaesar/tt"
@@ -15,3 +17,17 @@ func TestImg(t *testing.T) {
 	err = Save("testdata/test_1.bmp", img)
 	tt.Nil(t, err)
 }
+
+func TestWebP(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.NRGBA{R: 200, G: 50, B: 50, A: 255})
+
+	b, err := ToBytes(img, "webp")
+	tt.Nil(t, err)
+	tt.Equal(t, true, len(b) > 0)
+
+	out, err := ByteToImg(b)
+	tt.Nil(t, err)
+	tt.Equal(t, 4, out.Bounds().Dx())
+	tt.Equal(t, 4, out.Bounds().Dy())
+}
@@ -0,0 +1,89 @@
+package imgo
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+
+	"golang.org/x/image/tiff"
+)
+
+// EncodeOptions holds the per-format parameters used by EncodeWith,
+// SaveWith and ToBytesWith
+type EncodeOptions struct {
+	// Quality is the jpeg/webp encode quality, it ranges from 1 to 100.
+	// The standard library's jpeg encoder has no support for
+	// progressive output or custom chroma subsampling, so neither is
+	// configurable here.
+	Quality int
+
+	// CompressionLevel is the png.Encoder compression level
+	CompressionLevel png.CompressionLevel
+
+	// NumColors, Quantizer and Drawer are passed to gif.Options
+	NumColors int
+	Quantizer draw.Quantizer
+	Drawer    draw.Drawer
+
+	// Compression is the tiff.Options compression type
+	Compression tiff.CompressionType
+
+	// BMPAlpha selects the alpha-preserving BMP encoder (EncodeImgV5,
+	// a BITMAPV5HEADER with explicit RGBA bit masks) instead of
+	// golang.org/x/image/bmp, whose classic 40 byte header carries no
+	// alpha mask, so alpha is lost on the way back. Decoding
+	// auto-detects either layout from the DIB header size, so it needs
+	// no matching flag.
+	BMPAlpha bool
+	// TopDown writes BMP rows top to bottom instead of the classic
+	// bottom-up order. Only honoured together with BMPAlpha, since
+	// golang.org/x/image/bmp doesn't support top-down bitmaps.
+	TopDown bool
+}
+
+// DefaultEncodeOptions return the EncodeOptions used by Encode/Save/ToBytes
+func DefaultEncodeOptions() EncodeOptions {
+	return EncodeOptions{
+		Quality:          90,
+		CompressionLevel: png.DefaultCompression,
+		Compression:      tiff.Deflate,
+	}
+}
+
+// EncodeWith encode image to out using the format fm and opts, fm is
+// looked up in the format registry (see RegisterFormat)
+func EncodeWith(out io.Writer, img image.Image, fm string, opts EncodeOptions) error {
+	ft, ok := lookupFormat(fm)
+	if !ok {
+		return fmt.Errorf("imgo: EncodeWith: unknown format %q", fm)
+	}
+
+	return ft.encode(out, img, opts)
+}
+
+// SaveWith create a image file with the image.Image using opts
+func SaveWith(path string, img image.Image, opts EncodeOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return EncodeWith(f, img, getFm(path), opts)
+}
+
+// ToBytesWith trans image.Image to []byte using opts
+func ToBytesWith(img image.Image, fm string, opts EncodeOptions) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	err := EncodeWith(buf, img, fm, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,37 @@
+package imgo
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestToBytesWith(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < 8; i++ {
+		img.Set(i, i, color.RGBA{R: 255, A: 255})
+	}
+
+	opts := DefaultEncodeOptions()
+	opts.Quality = 50
+	b, err := ToBytesWith(img, "jpeg", opts)
+	tt.Nil(t, err)
+	tt.Equal(t, true, len(b) > 0)
+
+	opts.CompressionLevel = png.BestCompression
+	b, err = ToBytesWith(img, "png", opts)
+	tt.Nil(t, err)
+	tt.Equal(t, true, len(b) > 0)
+}
+
+func TestSaveWith(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	path := filepath.Join(t.TempDir(), "test_with.png")
+	err := SaveWith(path, img, DefaultEncodeOptions())
+	tt.Nil(t, err)
+}
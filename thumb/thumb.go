@@ -0,0 +1,221 @@
+// Package thumb generates multiple resized variants of an image.Image
+// concurrently, sharing a single decode of the source
+package thumb
+
+import (
+	"image"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"golang.org/x/image/draw"
+
+	"github.com/vcaesar/imgo"
+)
+
+// Fit describes how a source image is fitted into a thumbnail's
+// target box
+type Fit int
+
+const (
+	// Cover scales the image to fill the box, keeping its aspect
+	// ratio, and crops the centered overflow
+	Cover Fit = iota
+	// Contain scales the image to fit inside the box, keeping
+	// its aspect ratio and the full image visible
+	Contain
+	// Stretch scales width and height independently to match the box
+	Stretch
+)
+
+// ThumbSpec describes one resized variant to generate from a source
+// image
+type ThumbSpec struct {
+	Width   int
+	Height  int
+	Format  string
+	Quality int
+	Fit     Fit
+
+	// Path is where the result is saved, ignored when Writer is set
+	Path string
+	// Writer, when set, receives the encoded result instead of Path
+	Writer io.Writer
+}
+
+// Result is the outcome of generating one ThumbSpec
+type Result struct {
+	Spec ThumbSpec
+	Err  error
+}
+
+// Generate share a single decode of src and fan out the resize/encode
+// of every ThumbSpec to a worker pool sized by GOMAXPROCS, streaming
+// each result to its Path or Writer through imgo.EncodeWith
+func Generate(src image.Image, specs []ThumbSpec) []Result {
+	results := make([]Result, len(specs))
+	if len(specs) == 0 {
+		return results
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(specs) {
+		workers = len(specs)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = Result{Spec: specs[idx], Err: render(src, specs[idx])}
+			}
+		}()
+	}
+
+	for i := range specs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// FromFile read path with imgo.Read and Generate every ThumbSpec from
+// the decoded image
+func FromFile(path string, specs []ThumbSpec) ([]Result, error) {
+	src, err := imgo.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Generate(src, specs), nil
+}
+
+func render(src image.Image, spec ThumbSpec) error {
+	dst := resize(src, spec)
+
+	opts := imgo.DefaultEncodeOptions()
+	if spec.Quality > 0 {
+		opts.Quality = spec.Quality
+	}
+
+	fm := spec.Format
+	if fm == "" {
+		fm = "jpeg"
+	}
+
+	if spec.Writer != nil {
+		return imgo.EncodeWith(spec.Writer, dst, fm, opts)
+	}
+
+	f, err := os.Create(spec.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return imgo.EncodeWith(f, dst, fm, opts)
+}
+
+func resize(src image.Image, spec ThumbSpec) image.Image {
+	w, h := spec.Width, spec.Height
+
+	switch spec.Fit {
+	case Contain:
+		w, h = containSize(src.Bounds().Dx(), src.Bounds().Dy(), w, h)
+		return scale(src, w, h)
+	case Cover:
+		return cover(src, w, h)
+	default: // Stretch
+		return scale(src, w, h)
+	}
+}
+
+// scale resize src to exactly w x h, distorting its aspect ratio if
+// necessary; this is Stretch's behavior and Contain/Cover's last step
+func scale(src image.Image, w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// containSize shrink w/h to the largest box that both fits inside the
+// requested w/h and keeps the source's aspect ratio
+func containSize(srcW, srcH, w, h int) (int, int) {
+	if srcW == 0 || srcH == 0 || w == 0 || h == 0 {
+		return w, h
+	}
+
+	srcRatio := float64(srcW) / float64(srcH)
+	boxRatio := float64(w) / float64(h)
+	if srcRatio > boxRatio {
+		return w, int(float64(w) / srcRatio)
+	}
+	return int(float64(h) * srcRatio), h
+}
+
+// cover scale src to fill a w x h box, keeping its aspect ratio,
+// then crop the centered overflow so the result is exactly w x h
+func cover(src image.Image, w, h int) image.Image {
+	sb := src.Bounds()
+	srcW, srcH := sb.Dx(), sb.Dy()
+	if srcW == 0 || srcH == 0 || w == 0 || h == 0 {
+		return scale(src, w, h)
+	}
+
+	scaledW, scaledH := w, h
+	srcRatio := float64(srcW) / float64(srcH)
+	boxRatio := float64(w) / float64(h)
+	if srcRatio > boxRatio {
+		scaledW = int(float64(h) * srcRatio)
+	} else {
+		scaledH = int(float64(w) / srcRatio)
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledW, scaledH))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, sb, draw.Over, nil)
+
+	x0 := (scaledW - w) / 2
+	y0 := (scaledH - h) / 2
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			so := scaled.PixOffset(x+x0, y+y0)
+			do := dst.PixOffset(x, y)
+			copy(dst.Pix[do:do+4], scaled.Pix[so:so+4])
+		}
+	}
+
+	return dst
+}
+
+// Pyramid generate power-of-two mipmaps of img, halving its size until
+// either dimension would drop below minSize, useful for
+// OpenSeadragon-style tiled zoom. minSize below 1 is treated as 1, so
+// the halving always terminates instead of looping on 0x0 images.
+func Pyramid(img image.Image, minSize int) []image.Image {
+	if minSize < 1 {
+		minSize = 1
+	}
+
+	levels := []image.Image{img}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	for w/2 >= minSize && h/2 >= minSize {
+		w /= 2
+		h /= 2
+
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		prev := levels[len(levels)-1]
+		draw.CatmullRom.Scale(dst, dst.Bounds(), prev, prev.Bounds(), draw.Over, nil)
+		levels = append(levels, dst)
+	}
+
+	return levels
+}
@@ -0,0 +1,58 @@
+package thumb
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestGenerate(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 10), A: 255})
+		}
+	}
+
+	var coverBuf, stretchBuf bytes.Buffer
+	specs := []ThumbSpec{
+		{Width: 4, Height: 4, Format: "png", Fit: Cover, Writer: &coverBuf},
+		{Width: 4, Height: 4, Format: "png", Fit: Stretch, Writer: &stretchBuf},
+	}
+
+	results := Generate(src, specs)
+	for _, r := range results {
+		tt.Nil(t, r.Err)
+	}
+
+	tt.Equal(t, true, coverBuf.Len() > 0)
+	tt.Equal(t, true, stretchBuf.Len() > 0)
+	// Cover crops the overflow, Stretch distorts instead, so their
+	// encoded output should differ
+	tt.Equal(t, false, bytes.Equal(coverBuf.Bytes(), stretchBuf.Bytes()))
+}
+
+func TestPyramid(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	levels := Pyramid(src, 4)
+
+	tt.Equal(t, 3, len(levels))
+	tt.Equal(t, 4, levels[len(levels)-1].Bounds().Dx())
+}
+
+// TestPyramidMinSizeZero checks that a non-positive minSize is clamped
+// to 1 instead of looping forever on 0x0 images
+func TestPyramidMinSizeZero(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	levels := Pyramid(src, 0)
+	tt.Equal(t, 3, len(levels))
+	tt.Equal(t, 1, levels[len(levels)-1].Bounds().Dx())
+
+	levels = Pyramid(src, -10)
+	tt.Equal(t, 3, len(levels))
+	tt.Equal(t, 1, levels[len(levels)-1].Bounds().Dx())
+}
@@ -0,0 +1,18 @@
+//go:build cgo
+// +build cgo
+
+package imgo
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// encodeWebP encodes img as WebP using github.com/chai2010/webp, which
+// binds to libwebp via cgo
+func encodeWebP(w io.Writer, img image.Image, opts interface{}) error {
+	o := optsOrDefault(opts)
+	return webp.Encode(w, img, &webp.Options{Quality: float32(o.Quality)})
+}
@@ -0,0 +1,18 @@
+//go:build !cgo
+// +build !cgo
+
+package imgo
+
+import (
+	"errors"
+	"image"
+	"io"
+)
+
+// encodeWebP is the cgo-disabled stub: github.com/chai2010/webp binds
+// to libwebp via cgo, so it can't be linked into a CGO_ENABLED=0
+// build. Decoding (golang.org/x/image/webp) is pure Go and unaffected;
+// only encoding to webp is unavailable here.
+func encodeWebP(w io.Writer, img image.Image, opts interface{}) error {
+	return errors.New("imgo: webp encoding requires cgo (github.com/chai2010/webp); built with CGO_ENABLED=0")
+}